@@ -0,0 +1,252 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// migrateAuthTables creates the users/tokens tables and adds the user_id
+// and share_slug columns notes need to be scoped per-user.
+func (s *Server) migrateAuthTables() error {
+	_, err := s.db.Exec(`
+	CREATE TABLE IF NOT EXISTS users (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		username TEXT UNIQUE NOT NULL,
+		password_hash TEXT NOT NULL,
+		created_at TIMESTAMP
+	);
+	CREATE TABLE IF NOT EXISTS tokens (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL,
+		token_hash TEXT NOT NULL UNIQUE,
+		created_at TIMESTAMP,
+		FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+	);
+	`)
+	if err != nil {
+		return err
+	}
+
+	alterations := []string{
+		`ALTER TABLE notes ADD COLUMN user_id INTEGER DEFAULT 0`,
+		`ALTER TABLE notes ADD COLUMN share_slug TEXT`,
+	}
+	for _, a := range alterations {
+		if _, err := s.db.Exec(a); err != nil && !isDuplicateColumnErr(err) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+type contextKey string
+
+const userContextKeyKey contextKey = "user_id"
+
+func userIDFromContext(ctx context.Context) int {
+	id, _ := ctx.Value(userContextKeyKey).(int)
+	return id
+}
+
+// userOwnsNote reports whether noteID belongs to userID. Handlers use this
+// instead of folding the check into every query so ownership and the
+// resulting 404 ("don't reveal a note exists") stay consistent everywhere.
+func (s *Server) userOwnsNote(userID int, noteID string) bool {
+	var exists int
+	err := s.db.QueryRow("SELECT 1 FROM notes WHERE id = ? AND user_id = ?", noteID, userID).Scan(&exists)
+	return err == nil
+}
+
+// requireAuth validates the bearer token on every protected request and
+// injects the owning user's ID into the request context.
+func (s *Server) requireAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authHeader := r.Header.Get("Authorization")
+		token := strings.TrimPrefix(authHeader, "Bearer ")
+		if token == "" || token == authHeader {
+			respondWithError(w, http.StatusUnauthorized, "Missing or malformed Authorization header")
+			return
+		}
+
+		userID, ok := s.validateToken(token)
+		if !ok {
+			respondWithError(w, http.StatusUnauthorized, "Invalid or expired token")
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), userContextKeyKey, userID)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// hashToken is also used as the lookup key in the tokens table: since SHA-256
+// is preimage-resistant, indexing on the hash does not leak the raw token,
+// so the DB lookup itself need not be constant time.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// validateToken looks up the hash of token and compares it in constant time
+// against the stored hash to avoid leaking timing information to a caller
+// probing for a valid prefix.
+func (s *Server) validateToken(token string) (int, bool) {
+	hash := hashToken(token)
+
+	var userID int
+	var storedHash string
+	err := s.db.QueryRow("SELECT user_id, token_hash FROM tokens WHERE token_hash = ?", hash).Scan(&userID, &storedHash)
+	if err != nil {
+		return 0, false
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hash), []byte(storedHash)) != 1 {
+		return 0, false
+	}
+	return userID, true
+}
+
+type authRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+type authResponse struct {
+	Token string `json:"token"`
+}
+
+// register creates a new user with a bcrypt-hashed password.
+func (s *Server) register(w http.ResponseWriter, r *http.Request) {
+	var req authRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	defer r.Body.Close()
+
+	if req.Username == "" || req.Password == "" {
+		respondWithError(w, http.StatusBadRequest, "Username and password are required")
+		return
+	}
+
+	passwordHash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	result, err := s.db.Exec(
+		"INSERT INTO users(username, password_hash, created_at) VALUES(?, ?, ?)",
+		req.Username, string(passwordHash), time.Now(),
+	)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Username already taken")
+		return
+	}
+
+	userID, err := result.LastInsertId()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	token, err := s.issueToken(int(userID))
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, authResponse{Token: token})
+}
+
+// login verifies credentials and issues a fresh bearer token.
+func (s *Server) login(w http.ResponseWriter, r *http.Request) {
+	var req authRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	defer r.Body.Close()
+
+	var userID int
+	var passwordHash string
+	err := s.db.QueryRow("SELECT id, password_hash FROM users WHERE username = ?", req.Username).Scan(&userID, &passwordHash)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid username or password")
+		return
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(passwordHash), []byte(req.Password)); err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid username or password")
+		return
+	}
+
+	token, err := s.issueToken(userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, authResponse{Token: token})
+}
+
+// issueToken mints a random opaque bearer token and stores only its hash.
+func (s *Server) issueToken(userID int) (string, error) {
+	token := newUUID() + newUUID()
+	_, err := s.db.Exec(
+		"INSERT INTO tokens(user_id, token_hash, created_at) VALUES(?, ?, ?)",
+		userID, hashToken(token), time.Now(),
+	)
+	return token, err
+}
+
+// shareNote enables public read-only access to a note via a random slug.
+func (s *Server) shareNote(w http.ResponseWriter, r *http.Request) {
+	id := pathParam(r, "id")
+	userID := userIDFromContext(r.Context())
+
+	if !s.userOwnsNote(userID, id) {
+		respondWithError(w, http.StatusNotFound, "Note not found")
+		return
+	}
+
+	slug := newUUID()
+	if _, err := s.db.Exec("UPDATE notes SET share_slug = ? WHERE id = ? AND user_id = ?", slug, id, userID); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]string{"share_slug": slug})
+}
+
+// getSharedNote serves a note read-only via its public share slug; no
+// authentication required, matching the "public share" mode.
+func (s *Server) getSharedNote(w http.ResponseWriter, r *http.Request) {
+	slug := pathParam(r, "slug")
+
+	var note Note
+	var shareSlug sql.NullString
+	query := "SELECT id, user_id, title, content, created_at, last_modified, share_slug FROM notes WHERE share_slug = ?"
+	err := s.db.QueryRow(query, slug).Scan(&note.ID, &note.UserID, &note.Title, &note.Content, &note.CreatedAt, &note.LastModified, &shareSlug)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithError(w, http.StatusNotFound, "Note not found")
+		} else {
+			respondWithError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+	note.ShareSlug = shareSlug.String
+
+	respondWithJSON(w, http.StatusOK, note)
+}
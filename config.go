@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+	"reflect"
+	"time"
+)
+
+// Config is the typed representation of config.json. Host/port/db_path/tls
+// take effect only at startup; cors and log_level are hot-reloadable (see
+// watchConfig).
+type Config struct {
+	Host string `json:"host"`
+	Port string `json:"port"`
+
+	DBPath string `json:"db_path"`
+
+	CORS struct {
+		AllowedOrigins []string `json:"allowed_origins"`
+	} `json:"cors"`
+
+	TLS struct {
+		Cert string `json:"cert"`
+		Key  string `json:"key"`
+	} `json:"tls"`
+
+	LogLevel string `json:"log_level"`
+
+	RateLimit struct {
+		RequestsPerSecond float64 `json:"requests_per_second"`
+		Burst             int     `json:"burst"`
+	} `json:"rate_limit"`
+
+	Features map[string]bool `json:"features"`
+}
+
+// defaultConfig mirrors the hardcoded values the server used before
+// config.json existed, so a missing file is still a usable deployment.
+func defaultConfig() *Config {
+	cfg := &Config{
+		Host:     "",
+		Port:     "8080",
+		DBPath:   "./notetime.db",
+		LogLevel: "info",
+		Features: map[string]bool{},
+	}
+	// Deny all cross-origin requests until an operator opts specific origins
+	// in; see dynamicCORS for how an empty list is enforced.
+	cfg.CORS.AllowedOrigins = []string{}
+	cfg.RateLimit.RequestsPerSecond = 5
+	cfg.RateLimit.Burst = 10
+	return cfg
+}
+
+// LoadConfig reads and parses path into a Config, falling back to
+// defaultConfig() for any field the file omits. DB_PATH and PORT env vars
+// are honored as overrides for backwards compatibility with deployments
+// that predate config.json.
+func LoadConfig(path string) (*Config, error) {
+	cfg := defaultConfig()
+
+	if data, err := os.ReadFile(path); err == nil {
+		if err := json.Unmarshal(data, cfg); err != nil {
+			return nil, err
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	if v := os.Getenv("DB_PATH"); v != "" {
+		cfg.DBPath = v
+	}
+	if v := os.Getenv("PORT"); v != "" {
+		cfg.Port = v
+	}
+
+	return cfg, nil
+}
+
+const configPollInterval = 2 * time.Second
+
+// watchConfig polls path for changes and hot-reloads the fields that are
+// safe to change without restarting (CORS origins, log level). Fields that
+// require a restart (host, port, db_path, tls) are left untouched even if
+// the file changes.
+func (s *Server) watchConfig(path string) {
+	var lastMod time.Time
+	if info, err := os.Stat(path); err == nil {
+		lastMod = info.ModTime()
+	}
+
+	ticker := time.NewTicker(configPollInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		info, err := os.Stat(path)
+		if err != nil || !info.ModTime().After(lastMod) {
+			continue
+		}
+		lastMod = info.ModTime()
+
+		next, err := LoadConfig(path)
+		if err != nil {
+			log.Printf("config: reload failed, keeping previous config: %v", err)
+			continue
+		}
+
+		s.applyHotReload(next)
+	}
+}
+
+// applyHotReload swaps in the CORS and log-level fields of next, logging
+// each field that actually changed.
+func (s *Server) applyHotReload(next *Config) {
+	current := s.cfg()
+
+	updated := *current
+	if !reflect.DeepEqual(current.CORS.AllowedOrigins, next.CORS.AllowedOrigins) {
+		log.Printf("config: cors.allowed_origins changed: %v -> %v", current.CORS.AllowedOrigins, next.CORS.AllowedOrigins)
+		updated.CORS.AllowedOrigins = next.CORS.AllowedOrigins
+	}
+	if current.LogLevel != next.LogLevel {
+		log.Printf("config: log_level changed: %s -> %s", current.LogLevel, next.LogLevel)
+		updated.LogLevel = next.LogLevel
+		logLevelVar.Set(parseLogLevel(next.LogLevel))
+	}
+	if current.RateLimit != next.RateLimit {
+		log.Printf("config: rate_limit changed: %+v -> %+v", current.RateLimit, next.RateLimit)
+		updated.RateLimit = next.RateLimit
+	}
+
+	s.config.Store(&updated)
+}
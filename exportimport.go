@@ -0,0 +1,395 @@
+package main
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// sanitizeFilename strips any directory components from a client-supplied
+// filename so it's safe to use as a zip entry name or in a Content-Disposition
+// header. Without this, a crafted upload filename like "../../tmp/evil"
+// would let a later zip export write outside the extraction directory
+// (zip-slip).
+func sanitizeFilename(name string) string {
+	name = strings.ReplaceAll(name, `\`, "/")
+	name = filepath.Base(name)
+	switch name {
+	case ".", "..", "/", "":
+		return "attachment"
+	}
+	return name
+}
+
+// migrateImportExportTables adds the attachments table and the content_hash
+// column on notes that idempotent import relies on to detect re-imports.
+func (s *Server) migrateImportExportTables() error {
+	_, err := s.db.Exec(`
+	CREATE TABLE IF NOT EXISTS attachments (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		uuid TEXT UNIQUE NOT NULL,
+		note_id INTEGER NOT NULL,
+		filename TEXT,
+		content_type TEXT,
+		size INTEGER,
+		content_hash TEXT,
+		created_at TIMESTAMP,
+		FOREIGN KEY (note_id) REFERENCES notes (id) ON DELETE CASCADE
+	);
+	`)
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.db.Exec(`ALTER TABLE notes ADD COLUMN content_hash TEXT`); err != nil && !isDuplicateColumnErr(err) {
+		return err
+	}
+
+	return nil
+}
+
+// uploadDir returns where uploaded attachment binaries are stored, creating
+// it on first use. UPLOAD_DIR follows the same env-var convention as DB_PATH.
+func uploadDir() (string, error) {
+	dir := os.Getenv("UPLOAD_DIR")
+	if dir == "" {
+		dir = "./uploads"
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+func contentHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// noteExport is the full-fidelity representation used by both the "json"
+// exporter and the JSON body accepted by /api/import.
+type noteExport struct {
+	Note  Note   `json:"note"`
+	Lines []Line `json:"lines"`
+}
+
+// exportNote answers GET /api/notes/{id}/export?format=md|json|zip.
+func (s *Server) exportNote(w http.ResponseWriter, r *http.Request) {
+	id := pathParam(r, "id")
+	userID := userIDFromContext(r.Context())
+
+	if !s.userOwnsNote(userID, id) {
+		respondWithError(w, http.StatusNotFound, "Note not found")
+		return
+	}
+
+	note, lines, err := s.loadNoteExport(id)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	switch r.URL.Query().Get("format") {
+	case "json", "":
+		respondWithJSON(w, http.StatusOK, noteExport{Note: note, Lines: lines})
+	case "md":
+		w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+		w.Write([]byte(renderMarkdown(note, lines)))
+	case "zip":
+		if err := s.writeNoteZip(w, note, lines); err != nil {
+			respondWithError(w, http.StatusInternalServerError, err.Error())
+		}
+	default:
+		respondWithError(w, http.StatusBadRequest, "format must be one of md, json, zip")
+	}
+}
+
+func (s *Server) loadNoteExport(id string) (Note, []Line, error) {
+	var note Note
+	var shareSlug, storedHash sql.NullString
+	err := s.db.QueryRow(
+		"SELECT id, user_id, title, content, created_at, last_modified, share_slug, content_hash FROM notes WHERE id = ?", id,
+	).Scan(&note.ID, &note.UserID, &note.Title, &note.Content, &note.CreatedAt, &note.LastModified, &shareSlug, &storedHash)
+	if err != nil {
+		return Note{}, nil, err
+	}
+	note.ShareSlug = shareSlug.String
+
+	rows, err := s.db.Query(
+		"SELECT id, note_id, content, timestamp, lamport, client_uuid, revision, deleted FROM lines WHERE note_id = ? ORDER BY lamport ASC, client_uuid ASC", id,
+	)
+	if err != nil {
+		return Note{}, nil, err
+	}
+	defer rows.Close()
+
+	lines := []Line{}
+	for rows.Next() {
+		var l Line
+		if err := rows.Scan(&l.ID, &l.NoteID, &l.Content, &l.Timestamp, &l.Lamport, &l.ClientUUID, &l.Revision, &l.Deleted); err != nil {
+			return Note{}, nil, err
+		}
+		lines = append(lines, l)
+	}
+
+	return note, lines, nil
+}
+
+// renderMarkdown turns a note into a document where each Line becomes a
+// bulleted item prefixed with its RFC3339 timestamp.
+func renderMarkdown(note Note, lines []Line) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n", note.Title)
+	if note.Content != "" {
+		fmt.Fprintf(&b, "%s\n\n", note.Content)
+	}
+	for _, l := range lines {
+		if l.Deleted {
+			continue
+		}
+		fmt.Fprintf(&b, "- [%s] %s\n", l.Timestamp.Format(time.RFC3339), l.Content)
+	}
+	return b.String()
+}
+
+// writeNoteZip bundles the JSON export plus any referenced attachment
+// binaries into a zip archive streamed directly to w.
+func (s *Server) writeNoteZip(w http.ResponseWriter, note Note, lines []Line) error {
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="note-%d.zip"`, note.ID))
+
+	zw := zip.NewWriter(w)
+	defer zw.Close()
+
+	noteJSON, err := json.MarshalIndent(noteExport{Note: note, Lines: lines}, "", "  ")
+	if err != nil {
+		return err
+	}
+	noteFile, err := zw.Create("note.json")
+	if err != nil {
+		return err
+	}
+	if _, err := noteFile.Write(noteJSON); err != nil {
+		return err
+	}
+
+	rows, err := s.db.Query("SELECT uuid, filename FROM attachments WHERE note_id = ?", note.ID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	dir, err := uploadDir()
+	if err != nil {
+		return err
+	}
+
+	for rows.Next() {
+		var uuid, filename string
+		if err := rows.Scan(&uuid, &filename); err != nil {
+			return err
+		}
+		data, err := os.ReadFile(filepath.Join(dir, uuid))
+		if err != nil {
+			continue // attachment missing on disk; skip rather than fail the whole export
+		}
+		attFile, err := zw.Create("attachments/" + uuid + "-" + sanitizeFilename(filename))
+		if err != nil {
+			return err
+		}
+		if _, err := attFile.Write(data); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// importNote accepts the noteExport JSON document produced by the "json"
+// exporter. It is idempotent by content hash: re-importing the same
+// title+content for a user reuses the existing note instead of duplicating it.
+func (s *Server) importNote(w http.ResponseWriter, r *http.Request) {
+	userID := userIDFromContext(r.Context())
+
+	var payload noteExport
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	defer r.Body.Close()
+
+	hash := contentHash([]byte(payload.Note.Title + "\x00" + payload.Note.Content))
+
+	var existingID int
+	err := s.db.QueryRow("SELECT id FROM notes WHERE user_id = ? AND content_hash = ?", userID, hash).Scan(&existingID)
+	if err == nil {
+		respondWithJSON(w, http.StatusOK, map[string]interface{}{"id": existingID, "imported": false})
+		return
+	}
+
+	createdAt := payload.Note.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = time.Now()
+	}
+	lastModified := payload.Note.LastModified
+	if lastModified.IsZero() {
+		lastModified = createdAt
+	}
+
+	// The note and its lines are inserted as one transaction so a failure
+	// partway through (e.g. a bad line) can't leave a committed note with a
+	// content_hash that makes a retry of the same payload short-circuit
+	// above as an already-successful import missing its lines.
+	tx, err := s.db.Begin()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(
+		"INSERT INTO notes(user_id, title, content, created_at, last_modified, content_hash) VALUES(?, ?, ?, ?, ?, ?)",
+		userID, payload.Note.Title, payload.Note.Content, createdAt, lastModified, hash,
+	)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	noteID, err := result.LastInsertId()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	for _, l := range payload.Lines {
+		ts := l.Timestamp
+		if ts.IsZero() {
+			ts = time.Now()
+		}
+		clientUUID := l.ClientUUID
+		if clientUUID == "" {
+			clientUUID = newUUID()
+		}
+		lamport, err := nextLamportTx(tx, int(noteID))
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		_, err = tx.Exec(
+			"INSERT INTO lines(note_id, content, timestamp, lamport, client_uuid, revision, deleted) VALUES(?, ?, ?, ?, ?, ?, ?)",
+			noteID, l.Content, ts, lamport, clientUUID, 1, l.Deleted,
+		)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, map[string]interface{}{"id": noteID, "imported": true})
+}
+
+// uploadAttachment stores a multipart-uploaded file under UPLOAD_DIR and
+// records it so lines can reference it via attachment://{uuid}. Idempotent
+// by content hash: re-uploading identical bytes for the same note reuses
+// the existing attachment.
+func (s *Server) uploadAttachment(w http.ResponseWriter, r *http.Request) {
+	noteID := pathParam(r, "id")
+	userID := userIDFromContext(r.Context())
+
+	if !s.userOwnsNote(userID, noteID) {
+		respondWithError(w, http.StatusNotFound, "Note not found")
+		return
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Missing multipart file field")
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	hash := contentHash(data)
+
+	var existingUUID string
+	err = s.db.QueryRow("SELECT uuid FROM attachments WHERE note_id = ? AND content_hash = ?", noteID, hash).Scan(&existingUUID)
+	if err == nil {
+		respondWithJSON(w, http.StatusOK, map[string]string{"uuid": existingUUID})
+		return
+	}
+
+	dir, err := uploadDir()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	uuid := newUUID()
+	if err := os.WriteFile(filepath.Join(dir, uuid), data, 0o644); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	contentType := header.Header.Get("Content-Type")
+	_, err = s.db.Exec(
+		"INSERT INTO attachments(uuid, note_id, filename, content_type, size, content_hash, created_at) VALUES(?, ?, ?, ?, ?, ?, ?)",
+		uuid, noteID, sanitizeFilename(header.Filename), contentType, len(data), hash, time.Now(),
+	)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, map[string]string{"uuid": uuid})
+}
+
+// getAttachment serves a previously uploaded file, checking that the
+// requesting user owns the note the attachment belongs to.
+func (s *Server) getAttachment(w http.ResponseWriter, r *http.Request) {
+	uuid := pathParam(r, "uuid")
+	userID := userIDFromContext(r.Context())
+
+	var noteID int
+	var filename, contentType string
+	err := s.db.QueryRow(
+		`SELECT a.note_id, a.filename, a.content_type FROM attachments a
+		 JOIN notes n ON n.id = a.note_id
+		 WHERE a.uuid = ? AND n.user_id = ?`,
+		uuid, userID,
+	).Scan(&noteID, &filename, &contentType)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Attachment not found")
+		return
+	}
+
+	dir, err := uploadDir()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if contentType != "" {
+		w.Header().Set("Content-Type", contentType)
+	}
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`inline; filename="%s"`, sanitizeFilename(filename)))
+	http.ServeFile(w, r, filepath.Join(dir, uuid))
+}
@@ -0,0 +1,20 @@
+package main
+
+import "testing"
+
+func TestSanitizeFilename_StripsPathTraversal(t *testing.T) {
+	cases := map[string]string{
+		"report.pdf":             "report.pdf",
+		"../../../../tmp/evil":   "evil",
+		"../../etc/passwd":       "passwd",
+		`..\..\windows\evil.exe`: "evil.exe",
+		"":                       "attachment",
+		"..":                     "attachment",
+		"/":                      "attachment",
+	}
+	for in, want := range cases {
+		if got := sanitizeFilename(in); got != want {
+			t.Errorf("sanitizeFilename(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -1,57 +1,80 @@
+// Build with -tags sqlite_fts5 (see Makefile): migrateSearchIndex creates
+// FTS5 virtual tables, and mattn/go-sqlite3 only compiles FTS5 support in
+// under that tag. Without it, Initialize fails with "no such module: fts5".
 package main
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
+	"flag"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/gorilla/handlers"
-	"github.com/gorilla/mux"
 	_ "github.com/mattn/go-sqlite3"
 )
 
 // Note represents a note in the application
 type Note struct {
 	ID           int       `json:"id"`
+	UserID       int       `json:"user_id"`
 	Title        string    `json:"title"`
 	Content      string    `json:"content"`
 	CreatedAt    time.Time `json:"created_at"`
 	LastModified time.Time `json:"last_modified"`
+	ShareSlug    string    `json:"share_slug,omitempty"`
 }
 
-// Line represents a line in a note with timestamp
+// Line represents a line in a note with timestamp.
+// Lamport and ClientUUID together form the CRDT ordering key (lamport, client_uuid);
+// Revision and Deleted support edits and tombstone-based deletes for offline sync.
 type Line struct {
-	ID        int       `json:"id"`
-	NoteID    int       `json:"note_id"`
-	Content   string    `json:"content"`
-	Timestamp time.Time `json:"timestamp"`
+	ID         int       `json:"id"`
+	NoteID     int       `json:"note_id"`
+	Content    string    `json:"content"`
+	Timestamp  time.Time `json:"timestamp"`
+	Lamport    int64     `json:"lamport"`
+	ClientUUID string    `json:"client_uuid"`
+	Revision   int       `json:"revision"`
+	Deleted    bool      `json:"deleted"`
 }
 
 // Server struct to manage database and router
 type Server struct {
 	db     *sql.DB
-	router *mux.Router
+	router Router
+	routes []routeInfo
+	config *atomic.Value // holds *Config; swapped in on hot-reload
+
+	hubsMu sync.Mutex
+	hubs   map[int]*noteHub
+
+	rateLimiterMu    sync.Mutex
+	rateLimiterInst  *ipRateLimiter
+	rateLimiterRPS   float64
+	rateLimiterBurst int
 }
 
-func (s *Server) Initialize() error {
-	// Get database path from environment variable or use default
-	dbPath := os.Getenv("DB_PATH")
-	if dbPath == "" {
-		dbPath = "./notetime.db" // Default value if not specified
-	}
+func (s *Server) Initialize(cfg *Config) error {
+	s.config = &atomic.Value{}
+	s.config.Store(cfg)
 
 	// Initialize database
 	var err error
-	s.db, err = sql.Open("sqlite3", dbPath)
+	s.db, err = sql.Open("sqlite3", cfg.DBPath)
 	if err != nil {
 		return err
 	}
 
-	log.Printf("Using database at: %s", dbPath)
+	log.Printf("Using database at: %s", cfg.DBPath)
 
 	// Create tables if they don't exist
 	createTablesQuery := `
@@ -75,54 +98,201 @@ func (s *Server) Initialize() error {
 		return err
 	}
 
+	if err := s.migrateCRDTColumns(); err != nil {
+		return err
+	}
+
+	if err := s.migrateAuthTables(); err != nil {
+		return err
+	}
+
+	if err := s.migrateSearchIndex(); err != nil {
+		return err
+	}
+
+	if err := s.migrateImportExportTables(); err != nil {
+		return err
+	}
+
 	// Initialize router
-	s.router = mux.NewRouter()
+	s.router = newRouter()
+	s.hubs = make(map[int]*noteHub)
 	s.setupRoutes()
 	return nil
 }
 
-// Setup API routes
+// protected wraps a handler with requireAuth. Routes are registered
+// individually (rather than grouped under a subrouter) since Router has no
+// notion of route groups, only single-handler registration.
+func (s *Server) protected(h http.HandlerFunc) http.HandlerFunc {
+	wrapped := s.requireAuth(h)
+	return wrapped.ServeHTTP
+}
+
+// Setup API routes. Every route is mounted under /api/v1 via registerRoute,
+// which also keeps /api working as a temporary alias for existing clients.
 func (s *Server) setupRoutes() {
-	// Add health check endpoint
-	s.router.HandleFunc("/api/health", func(w http.ResponseWriter, r *http.Request) {
+	s.registerRoute("GET", "/health", func(w http.ResponseWriter, r *http.Request) {
 		respondWithJSON(w, http.StatusOK, map[string]string{"status": "healthy"})
-	}).Methods("GET")
+	})
+
+	// Auth endpoints (public)
+	s.registerRoute("POST", "/register", s.register)
+	s.registerRoute("POST", "/login", s.login)
 
-	// Existing routes
-	s.router.HandleFunc("/api/notes", s.getAllNotes).Methods("GET")
-	s.router.HandleFunc("/api/notes", s.createNote).Methods("POST")
-	s.router.HandleFunc("/api/notes/{id:[0-9]+}", s.getNote).Methods("GET")
-	s.router.HandleFunc("/api/notes/{id:[0-9]+}", s.updateNote).Methods("PUT")
-	s.router.HandleFunc("/api/notes/{id:[0-9]+}", s.deleteNote).Methods("DELETE")
+	// Public read-only access to shared notes
+	s.registerRoute("GET", "/shared/:slug", s.getSharedNote)
+
+	// Everything under /notes and /search is scoped to the authenticated user
+	s.registerRoute("GET", "/notes", s.protected(s.getAllNotes))
+	s.registerRoute("POST", "/notes", s.protected(s.createNote))
+	s.registerRoute("GET", "/notes/:id", s.protected(s.getNote))
+	s.registerRoute("PUT", "/notes/:id", s.protected(s.updateNote))
+	s.registerRoute("DELETE", "/notes/:id", s.protected(s.deleteNote))
+	s.registerRoute("POST", "/notes/:id/share", s.protected(s.shareNote))
 
 	// Lines endpoints
-	s.router.HandleFunc("/api/notes/{id:[0-9]+}/lines", s.getLines).Methods("GET")
-	s.router.HandleFunc("/api/notes/{id:[0-9]+}/lines", s.addLine).Methods("POST")
+	s.registerRoute("GET", "/notes/:id/lines", s.protected(s.getLines))
+	s.registerRoute("POST", "/notes/:id/lines", s.protected(s.addLine))
+
+	// Real-time collaboration
+	s.registerRoute("GET", "/notes/:id/ws", s.protected(s.serveNoteWS))
 
 	// Search endpoint
-	s.router.HandleFunc("/api/search", s.searchNotes).Methods("GET")
+	s.registerRoute("GET", "/search", s.protected(s.searchNotes))
+
+	// Import/export and attachments
+	s.registerRoute("GET", "/notes/:id/export", s.protected(s.exportNote))
+	s.registerRoute("POST", "/import", s.protected(s.importNote))
+	s.registerRoute("POST", "/notes/:id/attachments", s.protected(s.uploadAttachment))
+	s.registerRoute("GET", "/attachments/:uuid", s.protected(s.getAttachment))
 
-	// Serve static files for frontend
-	s.router.PathPrefix("/").Handler(http.FileServer(http.Dir("./frontend/build")))
+	// Route discovery
+	s.router.Handle("GET", "/api/v1/openapi.json", s.serveOpenAPI)
+
+	// Serve static files for frontend for anything not matched above
+	s.router.NotFound(http.FileServer(http.Dir("./frontend/build")))
+}
+
+// cfg returns the currently active configuration. CORS and log level may be
+// hot-reloaded at runtime; everything else is fixed for the process lifetime.
+func (s *Server) cfg() *Config {
+	return s.config.Load().(*Config)
 }
 
-// Start the server
-func (s *Server) Start(port string) {
-	// CORS handling
-	corsMiddleware := handlers.CORS(
-		handlers.AllowedOrigins([]string{"*"}),
-		handlers.AllowedMethods([]string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}),
-		handlers.AllowedHeaders([]string{"Content-Type", "Authorization"}),
-	)
-
-	log.Printf("Server started on port %s", port)
-	log.Fatal(http.ListenAndServe(":"+port, corsMiddleware(s.router)))
+// dynamicCORS re-reads the allowed origins on every request so a config
+// reload takes effect without restarting the server.
+func (s *Server) dynamicCORS(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origins := s.cfg().CORS.AllowedOrigins
+		if len(origins) == 0 {
+			// gorilla/handlers treats an empty AllowedOrigins as "allow any
+			// origin", which is the opposite of what a missing config should
+			// mean here: deny all cross-origin requests until an operator
+			// opts specific origins in.
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		handlers.CORS(
+			handlers.AllowedOrigins(origins),
+			handlers.AllowedMethods([]string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}),
+			handlers.AllowedHeaders([]string{"Content-Type", "Authorization"}),
+		)(next).ServeHTTP(w, r)
+	})
+}
+
+// rateLimiter returns the shared per-IP limiter, rebuilding it if the
+// configured rate or burst changed since it was last built.
+func (s *Server) rateLimiter(rps float64, burst int) *ipRateLimiter {
+	s.rateLimiterMu.Lock()
+	defer s.rateLimiterMu.Unlock()
+	if s.rateLimiterInst == nil || s.rateLimiterRPS != rps || s.rateLimiterBurst != burst {
+		s.rateLimiterInst = newIPRateLimiter(rps, burst)
+		s.rateLimiterRPS = rps
+		s.rateLimiterBurst = burst
+	}
+	return s.rateLimiterInst
+}
+
+// buildHandler composes the middleware pipeline around the router: request
+// ID first so every later layer can log it, then access logging, panic
+// recovery, rate limiting, gzip, and CORS innermost next to the router.
+func (s *Server) buildHandler() http.Handler {
+	var h http.Handler = s.router
+	h = s.dynamicCORS(h)
+	h = gzipMiddleware(h)
+	h = s.rateLimitMiddleware(h)
+	h = recoveryMiddleware(h)
+	h = accessLogMiddleware(h)
+	h = requestIDMiddleware(h)
+	return h
+}
+
+// Start runs the HTTP(S) server until ctx is cancelled, then drains
+// in-flight requests, closes the database, and disconnects every
+// WebSocket hub before returning.
+func (s *Server) Start(ctx context.Context) error {
+	cfg := s.cfg()
+	addr := fmt.Sprintf("%s:%s", cfg.Host, cfg.Port)
+
+	httpServer := &http.Server{
+		Addr:    addr,
+		Handler: s.buildHandler(),
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		log.Printf("Server started on %s", addr)
+		var err error
+		if cfg.TLS.Cert != "" && cfg.TLS.Key != "" {
+			err = httpServer.ListenAndServeTLS(cfg.TLS.Cert, cfg.TLS.Key)
+		} else {
+			err = httpServer.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
+			errCh <- err
+		}
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+
+	log.Printf("Shutting down: %v", ctx.Err())
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+
+	s.closeHubs()
+	return s.db.Close()
+}
+
+// closeHubs disconnects every connected WebSocket client so clients get a
+// clean close instead of a dropped connection during shutdown.
+func (s *Server) closeHubs() {
+	s.hubsMu.Lock()
+	defer s.hubsMu.Unlock()
+	for _, h := range s.hubs {
+		h.mu.Lock()
+		for c := range h.clients {
+			c.conn.Close()
+		}
+		h.mu.Unlock()
+	}
 }
 
 // API handlers
 
 // Get all notes
 func (s *Server) getAllNotes(w http.ResponseWriter, r *http.Request) {
+	userID := userIDFromContext(r.Context())
+
 	sortBy := r.URL.Query().Get("sort")
 	if sortBy == "" {
 		sortBy = "last_modified" // Default sorting
@@ -135,8 +305,8 @@ func (s *Server) getAllNotes(w http.ResponseWriter, r *http.Request) {
 		sortQuery = "ORDER BY last_modified DESC"
 	}
 
-	query := fmt.Sprintf("SELECT id, title, content, created_at, last_modified FROM notes %s", sortQuery)
-	rows, err := s.db.Query(query)
+	query := fmt.Sprintf("SELECT id, user_id, title, content, created_at, last_modified, share_slug FROM notes WHERE user_id = ? %s", sortQuery)
+	rows, err := s.db.Query(query, userID)
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -146,10 +316,12 @@ func (s *Server) getAllNotes(w http.ResponseWriter, r *http.Request) {
 	notes := []Note{}
 	for rows.Next() {
 		var n Note
-		if err := rows.Scan(&n.ID, &n.Title, &n.Content, &n.CreatedAt, &n.LastModified); err != nil {
+		var shareSlug sql.NullString
+		if err := rows.Scan(&n.ID, &n.UserID, &n.Title, &n.Content, &n.CreatedAt, &n.LastModified, &shareSlug); err != nil {
 			respondWithError(w, http.StatusInternalServerError, err.Error())
 			return
 		}
+		n.ShareSlug = shareSlug.String
 		notes = append(notes, n)
 	}
 
@@ -158,12 +330,13 @@ func (s *Server) getAllNotes(w http.ResponseWriter, r *http.Request) {
 
 // Get a specific note
 func (s *Server) getNote(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	id := vars["id"]
+	id := pathParam(r, "id")
+	userID := userIDFromContext(r.Context())
 
 	var note Note
-	query := "SELECT id, title, content, created_at, last_modified FROM notes WHERE id = ?"
-	err := s.db.QueryRow(query, id).Scan(&note.ID, &note.Title, &note.Content, &note.CreatedAt, &note.LastModified)
+	var shareSlug sql.NullString
+	query := "SELECT id, user_id, title, content, created_at, last_modified, share_slug FROM notes WHERE id = ? AND user_id = ?"
+	err := s.db.QueryRow(query, id, userID).Scan(&note.ID, &note.UserID, &note.Title, &note.Content, &note.CreatedAt, &note.LastModified, &shareSlug)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			respondWithError(w, http.StatusNotFound, "Note not found")
@@ -172,12 +345,15 @@ func (s *Server) getNote(w http.ResponseWriter, r *http.Request) {
 		}
 		return
 	}
+	note.ShareSlug = shareSlug.String
 
 	respondWithJSON(w, http.StatusOK, note)
 }
 
 // Create a new note
 func (s *Server) createNote(w http.ResponseWriter, r *http.Request) {
+	userID := userIDFromContext(r.Context())
+
 	var note Note
 	decoder := json.NewDecoder(r.Body)
 	if err := decoder.Decode(&note); err != nil {
@@ -190,14 +366,15 @@ func (s *Server) createNote(w http.ResponseWriter, r *http.Request) {
 	now := time.Now()
 	note.CreatedAt = now
 	note.LastModified = now
+	note.UserID = userID
 
 	// Default title if empty
 	if note.Title == "" {
 		note.Title = "Untitled Diary"
 	}
 
-	query := "INSERT INTO notes(title, content, created_at, last_modified) VALUES(?, ?, ?, ?)"
-	result, err := s.db.Exec(query, note.Title, note.Content, note.CreatedAt, note.LastModified)
+	query := "INSERT INTO notes(user_id, title, content, created_at, last_modified) VALUES(?, ?, ?, ?, ?)"
+	result, err := s.db.Exec(query, note.UserID, note.Title, note.Content, note.CreatedAt, note.LastModified)
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -215,8 +392,13 @@ func (s *Server) createNote(w http.ResponseWriter, r *http.Request) {
 
 // Update an existing note
 func (s *Server) updateNote(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	id := vars["id"]
+	id := pathParam(r, "id")
+	userID := userIDFromContext(r.Context())
+
+	if !s.userOwnsNote(userID, id) {
+		respondWithError(w, http.StatusNotFound, "Note not found")
+		return
+	}
 
 	var note Note
 	decoder := json.NewDecoder(r.Body)
@@ -229,24 +411,30 @@ func (s *Server) updateNote(w http.ResponseWriter, r *http.Request) {
 	// Update timestamp
 	note.LastModified = time.Now()
 
-	query := "UPDATE notes SET title = ?, content = ?, last_modified = ? WHERE id = ?"
-	_, err := s.db.Exec(query, note.Title, note.Content, note.LastModified, id)
+	query := "UPDATE notes SET title = ?, content = ?, last_modified = ? WHERE id = ? AND user_id = ?"
+	_, err := s.db.Exec(query, note.Title, note.Content, note.LastModified, id, userID)
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
 	note.ID = parseInt(id)
+	note.UserID = userID
 	respondWithJSON(w, http.StatusOK, note)
 }
 
 // Delete a note
 func (s *Server) deleteNote(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	id := vars["id"]
+	id := pathParam(r, "id")
+	userID := userIDFromContext(r.Context())
+
+	if !s.userOwnsNote(userID, id) {
+		respondWithError(w, http.StatusNotFound, "Note not found")
+		return
+	}
 
-	query := "DELETE FROM notes WHERE id = ?"
-	_, err := s.db.Exec(query, id)
+	query := "DELETE FROM notes WHERE id = ? AND user_id = ?"
+	_, err := s.db.Exec(query, id, userID)
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -257,10 +445,15 @@ func (s *Server) deleteNote(w http.ResponseWriter, r *http.Request) {
 
 // Get lines for a specific note
 func (s *Server) getLines(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	noteID := vars["id"]
+	noteID := pathParam(r, "id")
+	userID := userIDFromContext(r.Context())
 
-	query := "SELECT id, note_id, content, timestamp FROM lines WHERE note_id = ? ORDER BY timestamp ASC"
+	if !s.userOwnsNote(userID, noteID) {
+		respondWithError(w, http.StatusNotFound, "Note not found")
+		return
+	}
+
+	query := "SELECT id, note_id, content, timestamp, lamport, client_uuid, revision, deleted FROM lines WHERE note_id = ? ORDER BY lamport ASC, client_uuid ASC"
 	rows, err := s.db.Query(query, noteID)
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, err.Error())
@@ -271,7 +464,7 @@ func (s *Server) getLines(w http.ResponseWriter, r *http.Request) {
 	lines := []Line{}
 	for rows.Next() {
 		var l Line
-		if err := rows.Scan(&l.ID, &l.NoteID, &l.Content, &l.Timestamp); err != nil {
+		if err := rows.Scan(&l.ID, &l.NoteID, &l.Content, &l.Timestamp, &l.Lamport, &l.ClientUUID, &l.Revision, &l.Deleted); err != nil {
 			respondWithError(w, http.StatusInternalServerError, err.Error())
 			return
 		}
@@ -283,8 +476,14 @@ func (s *Server) getLines(w http.ResponseWriter, r *http.Request) {
 
 // Add a line to a note
 func (s *Server) addLine(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	noteID := vars["id"]
+	noteID := pathParam(r, "id")
+	noteIDInt := parseInt(noteID)
+	userID := userIDFromContext(r.Context())
+
+	if !s.userOwnsNote(userID, noteID) {
+		respondWithError(w, http.StatusNotFound, "Note not found")
+		return
+	}
 
 	var line Line
 	decoder := json.NewDecoder(r.Body)
@@ -294,62 +493,49 @@ func (s *Server) addLine(w http.ResponseWriter, r *http.Request) {
 	}
 	defer r.Body.Close()
 
-	// Set timestamp
+	// Set timestamp and CRDT ordering key
 	line.Timestamp = time.Now()
-	line.NoteID = parseInt(noteID)
+	line.NoteID = noteIDInt
+	if line.ClientUUID == "" {
+		line.ClientUUID = newUUID()
+	}
+	if line.Revision == 0 {
+		line.Revision = 1
+	}
 
-	query := "INSERT INTO lines(note_id, content, timestamp) VALUES(?, ?, ?)"
-	result, err := s.db.Exec(query, line.NoteID, line.Content, line.Timestamp)
+	lamport, err := s.nextLamport(noteIDInt)
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
+	line.Lamport = lamport
 
-	id, err := result.LastInsertId()
+	query := "INSERT INTO lines(note_id, content, timestamp, lamport, client_uuid, revision, deleted) VALUES(?, ?, ?, ?, ?, ?, ?)"
+	result, err := s.db.Exec(query, line.NoteID, line.Content, line.Timestamp, line.Lamport, line.ClientUUID, line.Revision, line.Deleted)
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
-	line.ID = int(id)
 
-	// Also update the last_modified timestamp of the parent note
-	updateQuery := "UPDATE notes SET last_modified = ? WHERE id = ?"
-	_, err = s.db.Exec(updateQuery, line.Timestamp, noteID)
+	id, err := result.LastInsertId()
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
+	line.ID = int(id)
 
-	respondWithJSON(w, http.StatusCreated, line)
-}
-
-// Search notes
-func (s *Server) searchNotes(w http.ResponseWriter, r *http.Request) {
-	query := r.URL.Query().Get("q")
-	if query == "" {
-		respondWithError(w, http.StatusBadRequest, "Search query is required")
-		return
-	}
-
-	searchQuery := "SELECT id, title, content, created_at, last_modified FROM notes WHERE title LIKE ? OR content LIKE ? ORDER BY last_modified DESC"
-	rows, err := s.db.Query(searchQuery, "%"+query+"%", "%"+query+"%")
+	// Also update the last_modified timestamp of the parent note
+	updateQuery := "UPDATE notes SET last_modified = ? WHERE id = ?"
+	_, err = s.db.Exec(updateQuery, line.Timestamp, noteID)
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
-	defer rows.Close()
 
-	notes := []Note{}
-	for rows.Next() {
-		var n Note
-		if err := rows.Scan(&n.ID, &n.Title, &n.Content, &n.CreatedAt, &n.LastModified); err != nil {
-			respondWithError(w, http.StatusInternalServerError, err.Error())
-			return
-		}
-		notes = append(notes, n)
-	}
+	s.hub(noteIDInt).broadcast(wsEvent{Type: "line_added", NoteID: noteIDInt, Line: &line})
+	s.hub(noteIDInt).broadcast(wsEvent{Type: "note_updated", NoteID: noteIDInt})
 
-	respondWithJSON(w, http.StatusOK, notes)
+	respondWithJSON(w, http.StatusCreated, line)
 }
 
 // Helper functions
@@ -375,16 +561,33 @@ func parseInt(s string) int {
 }
 
 func main() {
-	server := Server{}
-	err := server.Initialize()
+	configPath := flag.String("config", "", "path to config.json")
+	flag.Parse()
+
+	path := *configPath
+	if path == "" {
+		path = os.Getenv("CONFIG_PATH")
+	}
+	if path == "" {
+		path = "./config.json"
+	}
+
+	cfg, err := LoadConfig(path)
 	if err != nil {
-		log.Fatal("Failed to initialize server: ", err)
+		log.Fatal("Failed to load config: ", err)
 	}
 
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
+	server := Server{}
+	if err := server.Initialize(cfg); err != nil {
+		log.Fatal("Failed to initialize server: ", err)
 	}
 
-	server.Start(port)
+	go server.watchConfig(path)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := server.Start(ctx); err != nil {
+		log.Fatal("Server error: ", err)
+	}
 }
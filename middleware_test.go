@@ -0,0 +1,59 @@
+package main
+
+import (
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// TestServeNoteWS_ThroughMiddlewareStack dials a real WebSocket connection
+// through s.buildHandler() (request ID, access log, recovery, rate limit,
+// gzip, CORS) the way a deployed server actually serves /notes/:id/ws, so a
+// regression like statusRecorder/gzipResponseWriter losing Hijack would
+// surface here instead of only in a unit test of serveNoteWS directly.
+func TestServeNoteWS_ThroughMiddlewareStack(t *testing.T) {
+	s := newTestServer(t)
+
+	userResult, err := s.db.Exec(`INSERT INTO users(username, password_hash, created_at) VALUES('alice', 'x', ?)`, time.Now())
+	if err != nil {
+		t.Fatalf("insert user: %v", err)
+	}
+	userID, err := userResult.LastInsertId()
+	if err != nil {
+		t.Fatalf("user id: %v", err)
+	}
+	token, err := s.issueToken(int(userID))
+	if err != nil {
+		t.Fatalf("issueToken: %v", err)
+	}
+
+	noteResult, err := s.db.Exec(
+		`INSERT INTO notes(user_id, title, content, created_at, last_modified) VALUES(?, 'n', '', ?, ?)`,
+		userID, time.Now(), time.Now(),
+	)
+	if err != nil {
+		t.Fatalf("insert note: %v", err)
+	}
+	noteID, err := noteResult.LastInsertId()
+	if err != nil {
+		t.Fatalf("note id: %v", err)
+	}
+
+	srv := httptest.NewServer(s.buildHandler())
+	defer srv.Close()
+
+	wsURL := "ws" + strings.TrimPrefix(srv.URL, "http") + "/api/v1/notes/" + strconv.FormatInt(noteID, 10) + "/ws"
+	header := make(map[string][]string)
+	header["Authorization"] = []string{"Bearer " + token}
+
+	conn, resp, err := websocket.DefaultDialer.Dial(wsURL, header)
+	if err != nil {
+		t.Fatalf("dial: %v (status %v)", err, resp)
+	}
+	defer conn.Close()
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func createTestUser(t *testing.T, s *Server, username string) (userID int64, token string) {
+	t.Helper()
+	result, err := s.db.Exec(`INSERT INTO users(username, password_hash, created_at) VALUES(?, 'x', ?)`, username, time.Now())
+	if err != nil {
+		t.Fatalf("insert user: %v", err)
+	}
+	userID, err = result.LastInsertId()
+	if err != nil {
+		t.Fatalf("user id: %v", err)
+	}
+	token, err = s.issueToken(int(userID))
+	if err != nil {
+		t.Fatalf("issueToken: %v", err)
+	}
+	return userID, token
+}
+
+func createTestNote(t *testing.T, s *Server, userID int64) int64 {
+	t.Helper()
+	result, err := s.db.Exec(
+		`INSERT INTO notes(user_id, title, content, created_at, last_modified) VALUES(?, 'n', '', ?, ?)`,
+		userID, time.Now(), time.Now(),
+	)
+	if err != nil {
+		t.Fatalf("insert note: %v", err)
+	}
+	noteID, err := result.LastInsertId()
+	if err != nil {
+		t.Fatalf("note id: %v", err)
+	}
+	return noteID
+}
+
+// TestUpdateDeleteNote_RejectOtherUsersNote ensures PUT/DELETE on a note ID
+// that exists but belongs to a different user return 404, matching
+// getNote/getLines, instead of silently no-oping the user_id-scoped query
+// and still returning 200 as if the caller's note had been changed.
+func TestUpdateDeleteNote_RejectOtherUsersNote(t *testing.T) {
+	s := newTestServer(t)
+	ownerID, _ := createTestUser(t, s, "owner")
+	_, attackerToken := createTestUser(t, s, "attacker")
+	noteID := createTestNote(t, s, ownerID)
+
+	srv := httptest.NewServer(s.buildHandler())
+	defer srv.Close()
+
+	noteURL := srv.URL + "/api/v1/notes/" + strconv.FormatInt(noteID, 10)
+
+	req, _ := http.NewRequest(http.MethodPut, noteURL, strings.NewReader(`{"title":"hijacked","content":"x"}`))
+	req.Header.Set("Authorization", "Bearer "+attackerToken)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("PUT: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("PUT on another user's note: status = %d, want %d", resp.StatusCode, http.StatusNotFound)
+	}
+
+	req, _ = http.NewRequest(http.MethodDelete, noteURL, nil)
+	req.Header.Set("Authorization", "Bearer "+attackerToken)
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("DELETE: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("DELETE on another user's note: status = %d, want %d", resp.StatusCode, http.StatusNotFound)
+	}
+
+	// The note must still exist, untouched, for its real owner.
+	var title string
+	if err := s.db.QueryRow(`SELECT title FROM notes WHERE id = ?`, noteID).Scan(&title); err != nil {
+		t.Fatalf("note should still exist: %v", err)
+	}
+	if title != "n" {
+		t.Fatalf("note title = %q, want unchanged %q", title, "n")
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestClientIP_StripsPort(t *testing.T) {
+	r := &http.Request{RemoteAddr: "203.0.113.9:54321"}
+	if got := clientIP(r); got != "203.0.113.9" {
+		t.Fatalf("clientIP = %q, want %q", got, "203.0.113.9")
+	}
+}
+
+func TestClientIP_IgnoresForwardedFor(t *testing.T) {
+	r := &http.Request{RemoteAddr: "203.0.113.9:54321", Header: http.Header{
+		"X-Forwarded-For": []string{"198.51.100.1"},
+	}}
+	if got := clientIP(r); got != "203.0.113.9" {
+		t.Fatalf("clientIP = %q, want %q (X-Forwarded-For must not be trusted)", got, "203.0.113.9")
+	}
+}
+
+// TestRateLimitMiddleware_SharesLimiterAcrossConnections ensures the limiter
+// keys on IP, not on the ephemeral port of the TCP connection: distinct
+// RemoteAddr values from the same client IP must share one token bucket.
+func TestRateLimitMiddleware_SharesLimiterAcrossConnections(t *testing.T) {
+	s := &Server{}
+	cfg := defaultConfig()
+	cfg.RateLimit.RequestsPerSecond = 1
+	cfg.RateLimit.Burst = 1
+	s.config = &atomic.Value{}
+	s.config.Store(cfg)
+
+	handler := s.rateLimitMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req1 := httptest.NewRequest(http.MethodGet, "/", nil)
+	req1.RemoteAddr = "203.0.113.9:11111"
+	rec1 := httptest.NewRecorder()
+	handler.ServeHTTP(rec1, req1)
+	if rec1.Code != http.StatusOK {
+		t.Fatalf("first request: status = %d, want %d", rec1.Code, http.StatusOK)
+	}
+
+	// Same client IP, different ephemeral port: must share the first
+	// request's bucket and be rejected, not get a fresh one.
+	req2 := httptest.NewRequest(http.MethodGet, "/", nil)
+	req2.RemoteAddr = "203.0.113.9:22222"
+	rec2 := httptest.NewRecorder()
+	handler.ServeHTTP(rec2, req2)
+	if rec2.Code != http.StatusTooManyRequests {
+		t.Fatalf("second request from same IP, different port: status = %d, want %d", rec2.Code, http.StatusTooManyRequests)
+	}
+
+	// A genuinely different client IP gets its own bucket.
+	req3 := httptest.NewRequest(http.MethodGet, "/", nil)
+	req3.RemoteAddr = "198.51.100.1:11111"
+	rec3 := httptest.NewRecorder()
+	handler.ServeHTTP(rec3, req3)
+	if rec3.Code != http.StatusOK {
+		t.Fatalf("request from a different IP: status = %d, want %d", rec3.Code, http.StatusOK)
+	}
+}
+
+func TestIPRateLimiter_Allow(t *testing.T) {
+	l := newIPRateLimiter(1, 1)
+	if !l.allow("1.2.3.4") {
+		t.Fatal("first request should be allowed")
+	}
+	if l.allow("1.2.3.4") {
+		t.Fatal("second immediate request should be rate limited")
+	}
+	if !l.allow("5.6.7.8") {
+		t.Fatal("a different IP should have its own bucket")
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// Router is the thin seam between setupRoutes and the underlying mux
+// implementation, so handlers depend on pathParam(r, name) rather than a
+// concrete router's own vars type.
+type Router interface {
+	http.Handler
+	Handle(method, path string, handler http.HandlerFunc)
+	NotFound(handler http.Handler)
+}
+
+type httpRouter struct {
+	r *httprouter.Router
+}
+
+func newRouter() Router {
+	r := httprouter.New()
+	return &httpRouter{r: r}
+}
+
+type paramsContextKey struct{}
+
+func (hr *httpRouter) Handle(method, path string, handler http.HandlerFunc) {
+	hr.r.Handle(method, path, func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		ctx := context.WithValue(r.Context(), paramsContextKey{}, ps)
+		handler(w, r.WithContext(ctx))
+	})
+}
+
+func (hr *httpRouter) NotFound(handler http.Handler) {
+	hr.r.NotFound = handler
+}
+
+func (hr *httpRouter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	hr.r.ServeHTTP(w, r)
+}
+
+// pathParam reads a named path parameter populated by Router.Handle, so
+// handlers never import the router package directly.
+func pathParam(r *http.Request, name string) string {
+	ps, _ := r.Context().Value(paramsContextKey{}).(httprouter.Params)
+	return ps.ByName(name)
+}
+
+// routeInfo records one registered endpoint for the generated OpenAPI document.
+type routeInfo struct {
+	Method string
+	Path   string // v1 path, e.g. /api/v1/notes/:id
+}
+
+// registerRoute mounts handler under both /api/v1<path> (the canonical
+// surface) and /api<path> (a temporary alias for existing clients), and
+// records the v1 route for openapi.json.
+func (s *Server) registerRoute(method, path string, handler http.HandlerFunc) {
+	v1Path := "/api/v1" + path
+	aliasPath := "/api" + path
+
+	s.router.Handle(method, v1Path, handler)
+	s.router.Handle(method, aliasPath, handler)
+
+	s.routes = append(s.routes, routeInfo{Method: method, Path: v1Path})
+}
+
+// serveOpenAPI generates a minimal OpenAPI 3.0 document from the routes
+// registered via registerRoute, so clients can auto-discover the /api/v1 surface.
+func (s *Server) serveOpenAPI(w http.ResponseWriter, r *http.Request) {
+	paths := map[string]map[string]interface{}{}
+	for _, route := range s.routes {
+		// OpenAPI uses {param} while httprouter uses :param.
+		openAPIPath := httprouterPathToOpenAPI(route.Path)
+		if paths[openAPIPath] == nil {
+			paths[openAPIPath] = map[string]interface{}{}
+		}
+		paths[openAPIPath][strings.ToLower(route.Method)] = map[string]interface{}{
+			"responses": map[string]interface{}{
+				"200": map[string]string{"description": "OK"},
+			},
+		}
+	}
+
+	doc := map[string]interface{}{
+		"openapi": "3.0.0",
+		"info": map[string]string{
+			"title":   "nta-backend API",
+			"version": "v1",
+		},
+		"paths": paths,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(doc)
+}
+
+func httprouterPathToOpenAPI(path string) string {
+	segments := strings.Split(path, "/")
+	for i, seg := range segments {
+		if strings.HasPrefix(seg, ":") {
+			segments[i] = "{" + seg[1:] + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
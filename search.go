@@ -0,0 +1,206 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const defaultSearchLimit = 20
+
+// migrateSearchIndex creates FTS5 indexes mirroring notes(title, content)
+// and lines(content), wires triggers to keep them in sync on write, and
+// backfills any rows that predate the index.
+func (s *Server) migrateSearchIndex() error {
+	_, err := s.db.Exec(`
+	CREATE VIRTUAL TABLE IF NOT EXISTS notes_fts USING fts5(
+		title, content, content='notes', content_rowid='id'
+	);
+	CREATE VIRTUAL TABLE IF NOT EXISTS lines_fts USING fts5(
+		content, content='lines', content_rowid='id'
+	);
+
+	CREATE TRIGGER IF NOT EXISTS notes_fts_ai AFTER INSERT ON notes BEGIN
+		INSERT INTO notes_fts(rowid, title, content) VALUES (new.id, new.title, new.content);
+	END;
+	CREATE TRIGGER IF NOT EXISTS notes_fts_ad AFTER DELETE ON notes BEGIN
+		INSERT INTO notes_fts(notes_fts, rowid, title, content) VALUES ('delete', old.id, old.title, old.content);
+	END;
+	CREATE TRIGGER IF NOT EXISTS notes_fts_au AFTER UPDATE ON notes BEGIN
+		INSERT INTO notes_fts(notes_fts, rowid, title, content) VALUES ('delete', old.id, old.title, old.content);
+		INSERT INTO notes_fts(rowid, title, content) VALUES (new.id, new.title, new.content);
+	END;
+
+	CREATE TRIGGER IF NOT EXISTS lines_fts_ai AFTER INSERT ON lines BEGIN
+		INSERT INTO lines_fts(rowid, content) VALUES (new.id, new.content);
+	END;
+	CREATE TRIGGER IF NOT EXISTS lines_fts_ad AFTER DELETE ON lines BEGIN
+		INSERT INTO lines_fts(lines_fts, rowid, content) VALUES ('delete', old.id, old.content);
+	END;
+	CREATE TRIGGER IF NOT EXISTS lines_fts_au AFTER UPDATE ON lines BEGIN
+		INSERT INTO lines_fts(lines_fts, rowid, content) VALUES ('delete', old.id, old.content);
+		INSERT INTO lines_fts(rowid, content) VALUES (new.id, new.content);
+	END;
+	`)
+	if err != nil {
+		return err
+	}
+
+	// One-shot backfill for rows written before the index existed.
+	_, err = s.db.Exec(`
+	INSERT INTO notes_fts(rowid, title, content)
+	SELECT id, title, content FROM notes WHERE id NOT IN (SELECT rowid FROM notes_fts);
+
+	INSERT INTO lines_fts(rowid, content)
+	SELECT id, content FROM lines WHERE id NOT IN (SELECT rowid FROM lines_fts);
+	`)
+	return err
+}
+
+// searchHit is one match, either a whole note or a single timestamped line
+// within one, ranked by SQLite's bm25() and carrying a snippet() highlight.
+type searchHit struct {
+	Type      string     `json:"type"` // "note" or "line"
+	NoteID    int        `json:"note_id"`
+	LineID    int        `json:"line_id,omitempty"`
+	Title     string     `json:"title,omitempty"`
+	Rank      float64    `json:"rank"`
+	Snippet   string     `json:"snippet"`
+	Timestamp *time.Time `json:"timestamp,omitempty"`
+}
+
+// searchNotes answers /api/search?q=&limit=&offset=&scope=notes|lines|both.
+// q is passed straight through to FTS5 so callers can use its native
+// boolean/phrase syntax ("foo bar", foo OR bar, -baz).
+func (s *Server) searchNotes(w http.ResponseWriter, r *http.Request) {
+	userID := userIDFromContext(r.Context())
+
+	q := r.URL.Query().Get("q")
+	if q == "" {
+		respondWithError(w, http.StatusBadRequest, "Search query is required")
+		return
+	}
+
+	scope := r.URL.Query().Get("scope")
+	if scope == "" {
+		scope = "both"
+	}
+	if scope != "notes" && scope != "lines" && scope != "both" {
+		respondWithError(w, http.StatusBadRequest, "scope must be one of notes, lines, both")
+		return
+	}
+
+	limit := queryInt(r, "limit", defaultSearchLimit)
+	offset := queryInt(r, "offset", 0)
+
+	hits := []searchHit{}
+
+	if scope == "notes" || scope == "both" {
+		noteHits, err := s.searchNoteHits(userID, q)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		hits = append(hits, noteHits...)
+	}
+
+	if scope == "lines" || scope == "both" {
+		lineHits, err := s.searchLineHits(userID, q)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		hits = append(hits, lineHits...)
+	}
+
+	sort.Slice(hits, func(i, j int) bool { return hits[i].Rank < hits[j].Rank })
+
+	hits = paginate(hits, limit, offset)
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"results": hits,
+		"limit":   limit,
+		"offset":  offset,
+	})
+}
+
+func (s *Server) searchNoteHits(userID int, q string) ([]searchHit, error) {
+	query := `
+	SELECT n.id, n.title, bm25(notes_fts) AS rank,
+		snippet(notes_fts, 1, '<mark>', '</mark>', '...', 10)
+	FROM notes_fts
+	JOIN notes n ON n.id = notes_fts.rowid
+	WHERE notes_fts MATCH ? AND n.user_id = ?
+	ORDER BY rank
+	`
+	rows, err := s.db.Query(query, q, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	hits := []searchHit{}
+	for rows.Next() {
+		var h searchHit
+		h.Type = "note"
+		if err := rows.Scan(&h.NoteID, &h.Title, &h.Rank, &h.Snippet); err != nil {
+			return nil, err
+		}
+		hits = append(hits, h)
+	}
+	return hits, nil
+}
+
+func (s *Server) searchLineHits(userID int, q string) ([]searchHit, error) {
+	query := `
+	SELECT l.id, l.note_id, l.timestamp, bm25(lines_fts) AS rank,
+		snippet(lines_fts, 0, '<mark>', '</mark>', '...', 10)
+	FROM lines_fts
+	JOIN lines l ON l.id = lines_fts.rowid
+	JOIN notes n ON n.id = l.note_id
+	WHERE lines_fts MATCH ? AND n.user_id = ?
+	ORDER BY rank
+	`
+	rows, err := s.db.Query(query, q, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	hits := []searchHit{}
+	for rows.Next() {
+		var h searchHit
+		var ts time.Time
+		h.Type = "line"
+		if err := rows.Scan(&h.LineID, &h.NoteID, &ts, &h.Rank, &h.Snippet); err != nil {
+			return nil, err
+		}
+		h.Timestamp = &ts
+		hits = append(hits, h)
+	}
+	return hits, nil
+}
+
+func paginate(hits []searchHit, limit, offset int) []searchHit {
+	if offset >= len(hits) {
+		return []searchHit{}
+	}
+	end := offset + limit
+	if end > len(hits) {
+		end = len(hits)
+	}
+	return hits[offset:end]
+}
+
+func queryInt(r *http.Request, param string, def int) int {
+	raw := r.URL.Query().Get(param)
+	if raw == "" {
+		return def
+	}
+	v, err := strconv.Atoi(raw)
+	if err != nil || v < 0 {
+		return def
+	}
+	return v
+}
@@ -0,0 +1,357 @@
+package main
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// migrateCRDTColumns adds the columns needed for per-line CRDT sync to a
+// pre-existing lines table and creates the per-note Lamport clock table.
+// ALTER TABLE ... ADD COLUMN fails if the column already exists, so errors
+// are tolerated here; only unexpected failures (e.g. a locked DB) surface.
+func (s *Server) migrateCRDTColumns() error {
+	migrations := []string{
+		`ALTER TABLE lines ADD COLUMN lamport INTEGER DEFAULT 0`,
+		`ALTER TABLE lines ADD COLUMN client_uuid TEXT DEFAULT ''`,
+		`ALTER TABLE lines ADD COLUMN revision INTEGER DEFAULT 1`,
+		`ALTER TABLE lines ADD COLUMN deleted BOOLEAN DEFAULT 0`,
+	}
+	for _, m := range migrations {
+		if _, err := s.db.Exec(m); err != nil && !isDuplicateColumnErr(err) {
+			return err
+		}
+	}
+
+	_, err := s.db.Exec(`
+	CREATE TABLE IF NOT EXISTS note_clocks (
+		note_id INTEGER PRIMARY KEY,
+		lamport INTEGER NOT NULL DEFAULT 0
+	);
+	`)
+	return err
+}
+
+func isDuplicateColumnErr(err error) bool {
+	return err != nil && (err.Error() == "duplicate column name: lamport" ||
+		err.Error() == "duplicate column name: client_uuid" ||
+		err.Error() == "duplicate column name: revision" ||
+		err.Error() == "duplicate column name: deleted")
+}
+
+// nextLamport atomically advances and returns the persisted Lamport clock for a note.
+func (s *Server) nextLamport(noteID int) (int64, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	lamport, err := nextLamportTx(tx, noteID)
+	if err != nil {
+		return 0, err
+	}
+
+	return lamport, tx.Commit()
+}
+
+// nextLamportTx is nextLamport's logic run against a caller-supplied
+// transaction, so a multi-statement operation (e.g. importNote) can advance
+// the clock as part of its own transaction instead of nesting one.
+func nextLamportTx(tx *sql.Tx, noteID int) (int64, error) {
+	if _, err := tx.Exec(`INSERT OR IGNORE INTO note_clocks(note_id, lamport) VALUES(?, 0)`, noteID); err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec(`UPDATE note_clocks SET lamport = lamport + 1 WHERE note_id = ?`, noteID); err != nil {
+		return 0, err
+	}
+
+	var lamport int64
+	if err := tx.QueryRow(`SELECT lamport FROM note_clocks WHERE note_id = ?`, noteID).Scan(&lamport); err != nil {
+		return 0, err
+	}
+
+	return lamport, nil
+}
+
+func newUUID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// wsEvent is the envelope broadcast to every subscriber of a note's hub.
+type wsEvent struct {
+	Type     string    `json:"type"` // line_added, line_edited, note_updated, presence
+	NoteID   int       `json:"note_id"`
+	Line     *Line     `json:"line,omitempty"`
+	Presence *presence `json:"presence,omitempty"`
+}
+
+// snapshotEvent is sent once to a client right after it connects so late
+// joiners can catch up on the authoritative log without missing events
+// emitted while the snapshot query was running.
+type snapshotEvent struct {
+	Type  string `json:"type"`
+	Lines []Line `json:"lines"`
+}
+
+type presence struct {
+	ClientUUID  string `json:"client_uuid"`
+	DisplayName string `json:"display_name"`
+	Online      bool   `json:"online"`
+}
+
+const coalesceWindow = 150 * time.Millisecond
+
+// noteHub fans out CRDT events to every client currently viewing a note.
+type noteHub struct {
+	mu      sync.Mutex
+	clients map[*wsClient]bool
+
+	pending   *wsEvent
+	flushTime *time.Timer
+}
+
+type wsClient struct {
+	conn     *websocket.Conn
+	send     chan wsEvent
+	presence presence
+}
+
+func newNoteHub() *noteHub {
+	return &noteHub{clients: make(map[*wsClient]bool)}
+}
+
+func (h *noteHub) register(c *wsClient) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.clients[c] = true
+}
+
+func (h *noteHub) unregister(c *wsClient) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.clients[c]; ok {
+		delete(h.clients, c)
+		close(c.send)
+	}
+}
+
+// broadcast coalesces bursts of note_updated events (e.g. rapid keystrokes)
+// into a single flush per coalesceWindow; every other event type is sent
+// immediately since clients need line_added/line_edited/presence as they happen.
+func (h *noteHub) broadcast(evt wsEvent) {
+	if evt.Type != "note_updated" {
+		h.fanOut(evt)
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.pending = &evt
+	if h.flushTime == nil {
+		h.flushTime = time.AfterFunc(coalesceWindow, func() {
+			h.mu.Lock()
+			pending := h.pending
+			h.pending = nil
+			h.flushTime = nil
+			h.mu.Unlock()
+			if pending != nil {
+				h.fanOut(*pending)
+			}
+		})
+	}
+}
+
+func (h *noteHub) fanOut(evt wsEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for c := range h.clients {
+		select {
+		case c.send <- evt:
+		default:
+			log.Printf("ws: dropping slow client for note %d", evt.NoteID)
+		}
+	}
+}
+
+func (s *Server) hub(noteID int) *noteHub {
+	s.hubsMu.Lock()
+	defer s.hubsMu.Unlock()
+	h, ok := s.hubs[noteID]
+	if !ok {
+		h = newNoteHub()
+		s.hubs[noteID] = h
+	}
+	return h
+}
+
+var wsUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	// The frontend is served from the same origin as the API (see setupRoutes),
+	// so same-origin checks are left to the browser; no cross-site WS clients exist yet.
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// serveNoteWS upgrades the connection, sends an initial snapshot of the
+// note's lines so late joiners can catch up, then relays the client's
+// CRDT events (new/edited lines, presence) to every other subscriber.
+func (s *Server) serveNoteWS(w http.ResponseWriter, r *http.Request) {
+	idParam := pathParam(r, "id")
+	noteID := parseInt(idParam)
+	userID := userIDFromContext(r.Context())
+
+	if !s.userOwnsNote(userID, idParam) {
+		respondWithError(w, http.StatusNotFound, "Note not found")
+		return
+	}
+
+	displayName := r.URL.Query().Get("display_name")
+	if displayName == "" {
+		displayName = "Anonymous"
+	}
+	clientUUID := r.URL.Query().Get("client_uuid")
+	if clientUUID == "" {
+		clientUUID = newUUID()
+	}
+
+	conn, err := wsUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Printf("ws: upgrade failed: %v", err)
+		return
+	}
+
+	client := &wsClient{
+		conn:     conn,
+		send:     make(chan wsEvent, 32),
+		presence: presence{ClientUUID: clientUUID, DisplayName: displayName, Online: true},
+	}
+
+	hub := s.hub(noteID)
+	hub.register(client)
+	hub.broadcast(wsEvent{Type: "presence", NoteID: noteID, Presence: &client.presence})
+
+	lines, err := s.snapshotLines(noteID)
+	if err != nil {
+		log.Printf("ws: snapshot failed for note %d: %v", noteID, err)
+	} else if err := conn.WriteJSON(snapshotEvent{Type: "snapshot", Lines: lines}); err != nil {
+		log.Printf("ws: failed to write snapshot: %v", err)
+	}
+
+	go s.writePump(hub, client)
+	s.readPump(hub, client, noteID)
+}
+
+func (s *Server) snapshotLines(noteID int) ([]Line, error) {
+	rows, err := s.db.Query(
+		"SELECT id, note_id, content, timestamp, lamport, client_uuid, revision, deleted FROM lines WHERE note_id = ? ORDER BY lamport ASC, client_uuid ASC",
+		noteID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	lines := []Line{}
+	for rows.Next() {
+		var l Line
+		if err := rows.Scan(&l.ID, &l.NoteID, &l.Content, &l.Timestamp, &l.Lamport, &l.ClientUUID, &l.Revision, &l.Deleted); err != nil {
+			return nil, err
+		}
+		lines = append(lines, l)
+	}
+	return lines, nil
+}
+
+func (s *Server) writePump(hub *noteHub, c *wsClient) {
+	for evt := range c.send {
+		if err := c.conn.WriteJSON(evt); err != nil {
+			return
+		}
+	}
+}
+
+// readPump relays client-originated edits (line_edited) and tombstoned
+// deletes into the authoritative log, applying CRDT conflict resolution:
+// the highest (lamport, client_uuid) for a given (line_id, revision) wins.
+func (s *Server) readPump(hub *noteHub, c *wsClient, noteID int) {
+	defer func() {
+		hub.unregister(c)
+		c.presence.Online = false
+		hub.broadcast(wsEvent{Type: "presence", NoteID: noteID, Presence: &c.presence})
+		c.conn.Close()
+	}()
+
+	for {
+		var evt wsEvent
+		if err := c.conn.ReadJSON(&evt); err != nil {
+			return
+		}
+		if evt.Type != "line_edited" || evt.Line == nil {
+			continue
+		}
+
+		if err := s.applyLineEdit(noteID, *evt.Line); err != nil {
+			log.Printf("ws: failed to apply edit for note %d: %v", noteID, err)
+			continue
+		}
+		hub.broadcast(wsEvent{Type: "line_edited", NoteID: noteID, Line: evt.Line})
+	}
+}
+
+// applyLineEdit stores an edit (or tombstone delete) as a new revision,
+// winning only if its (lamport, client_uuid) beats what is already stored
+// for that (line_id, revision). Every query is scoped to noteID, the note
+// the client is actually connected to, so an edit can never read or write a
+// line belonging to a different note.
+func (s *Server) applyLineEdit(noteID int, line Line) error {
+	var existingLamport int64
+	var existingUUID string
+	err := s.db.QueryRow(
+		`SELECT lamport, client_uuid FROM lines WHERE id = ? AND revision = ? AND note_id = ?`,
+		line.ID, line.Revision, noteID,
+	).Scan(&existingLamport, &existingUUID)
+
+	if err == nil {
+		if line.Lamport < existingLamport || (line.Lamport == existingLamport && line.ClientUUID <= existingUUID) {
+			return nil // existing revision already wins
+		}
+		_, err = s.db.Exec(
+			`UPDATE lines SET content = ?, lamport = ?, client_uuid = ?, deleted = ? WHERE id = ? AND revision = ? AND note_id = ?`,
+			line.Content, line.Lamport, line.ClientUUID, line.Deleted, line.ID, line.Revision, noteID,
+		)
+		return err
+	}
+	if err != sql.ErrNoRows {
+		return err
+	}
+
+	// No existing revision for this line under noteID. If line.ID already
+	// belongs to another note, reject the edit instead of inserting a new
+	// revision under the wrong note.
+	var owningNoteID int
+	switch err := s.db.QueryRow(`SELECT note_id FROM lines WHERE id = ?`, line.ID).Scan(&owningNoteID); {
+	case err == nil:
+		if owningNoteID != noteID {
+			return fmt.Errorf("line %d belongs to note %d, not note %d", line.ID, owningNoteID, noteID)
+		}
+	case err != sql.ErrNoRows:
+		return err
+	}
+
+	_, err = s.db.Exec(
+		`INSERT INTO lines(id, note_id, content, timestamp, lamport, client_uuid, revision, deleted) VALUES(?, ?, ?, ?, ?, ?, ?, ?)`,
+		line.ID, noteID, line.Content, time.Now(), line.Lamport, line.ClientUUID, line.Revision, line.Deleted,
+	)
+	return err
+}
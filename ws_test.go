@@ -0,0 +1,86 @@
+package main
+
+import (
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func newTestServer(t *testing.T) *Server {
+	t.Helper()
+	cfg := defaultConfig()
+	cfg.DBPath = ":memory:"
+	s := &Server{}
+	if err := s.Initialize(cfg); err != nil {
+		t.Fatalf("Initialize: %v", err)
+	}
+	return s
+}
+
+func insertLine(t *testing.T, s *Server, noteID int, line Line) {
+	t.Helper()
+	if _, err := s.db.Exec(
+		`INSERT INTO lines(id, note_id, content, timestamp, lamport, client_uuid, revision, deleted) VALUES(?, ?, ?, ?, ?, ?, ?, ?)`,
+		line.ID, noteID, line.Content, line.Timestamp, line.Lamport, line.ClientUUID, line.Revision, line.Deleted,
+	); err != nil {
+		t.Fatalf("insertLine: %v", err)
+	}
+}
+
+func TestApplyLineEdit_UpdatesOwnNote(t *testing.T) {
+	s := newTestServer(t)
+	insertLine(t, s, 1, Line{ID: 1, Content: "hello", Lamport: 1, ClientUUID: "a", Revision: 0})
+
+	err := s.applyLineEdit(1, Line{ID: 1, Content: "world", Lamport: 2, ClientUUID: "a", Revision: 0})
+	if err != nil {
+		t.Fatalf("applyLineEdit: %v", err)
+	}
+
+	var content string
+	if err := s.db.QueryRow(`SELECT content FROM lines WHERE id = 1`).Scan(&content); err != nil {
+		t.Fatalf("query: %v", err)
+	}
+	if content != "world" {
+		t.Fatalf("content = %q, want %q", content, "world")
+	}
+}
+
+func TestApplyLineEdit_RejectsCrossNoteEdit(t *testing.T) {
+	s := newTestServer(t)
+	insertLine(t, s, 1, Line{ID: 1, Content: "note 1 secret", Lamport: 1, ClientUUID: "a", Revision: 0})
+	insertLine(t, s, 2, Line{ID: 2, Content: "note 2 line", Lamport: 1, ClientUUID: "b", Revision: 0})
+
+	// A client connected to note 2 forges an edit targeting note 1's line,
+	// with a (lamport, client_uuid) that would win the CRDT merge.
+	err := s.applyLineEdit(2, Line{ID: 1, Content: "overwritten", Lamport: 99, ClientUUID: "z", Revision: 0})
+	if err == nil {
+		t.Fatal("applyLineEdit: expected error for cross-note edit, got nil")
+	}
+
+	var content string
+	if err := s.db.QueryRow(`SELECT content FROM lines WHERE id = 1`).Scan(&content); err != nil {
+		t.Fatalf("query: %v", err)
+	}
+	if content != "note 1 secret" {
+		t.Fatalf("note 1's line was modified: content = %q", content)
+	}
+}
+
+func TestApplyLineEdit_DoesNotMatchRevisionAcrossNotes(t *testing.T) {
+	s := newTestServer(t)
+	insertLine(t, s, 1, Line{ID: 1, Content: "note 1 line", Lamport: 5, ClientUUID: "a", Revision: 0})
+
+	// note 2 has no line with id 1, so this must insert a new row scoped to
+	// note 2 rather than matching note 1's (id, revision) and updating it.
+	if err := s.applyLineEdit(2, Line{ID: 5, Content: "note 2 line", Lamport: 1, ClientUUID: "b", Revision: 0}); err != nil {
+		t.Fatalf("applyLineEdit: %v", err)
+	}
+
+	var noteID int
+	if err := s.db.QueryRow(`SELECT note_id FROM lines WHERE id = 5`).Scan(&noteID); err != nil {
+		t.Fatalf("query: %v", err)
+	}
+	if noteID != 2 {
+		t.Fatalf("note_id = %d, want 2", noteID)
+	}
+}